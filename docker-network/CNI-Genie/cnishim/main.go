@@ -0,0 +1,117 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Command cnishim is the CNI binary kubelet actually invokes. It does no
+Kubernetes API work itself: it serializes the skel.CmdArgs it was called
+with into a cniserver.Request and POSTs it over a unix socket to the
+cniserver daemon, then prints back whatever types.Result (or error) comes
+out, exactly as the CNI spec expects of the binary in /opt/cni/bin.
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/Huawei-PaaS/CNI-Genie/pkg/cniserver"
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/types/current"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+// socketPath is where the cniserver is expected to be listening. It isn't
+// configurable per-call because the shim has no netconf parsing of its
+// own - that's precisely the work being pushed into the server.
+const socketPath = cniserver.DefaultSocketPath
+
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+			return net.Dial("unix", socketPath)
+		},
+	},
+}
+
+func main() {
+	skel.PluginMain(cmdAdd, cmdCheck, cmdDel, version.All, "CNI Genie shim")
+}
+
+func cmdAdd(args *skel.CmdArgs) error {
+	result, err := callServer(cniserver.CmdAdd, args)
+	if err != nil {
+		return err
+	}
+	currResult, err := current.NewResultFromResult(result)
+	if err != nil {
+		return err
+	}
+	return types.PrintResult(currResult, currResult.CNIVersion)
+}
+
+func cmdCheck(args *skel.CmdArgs) error {
+	_, err := callServer(cniserver.CmdCheck, args)
+	return err
+}
+
+func cmdDel(args *skel.CmdArgs) error {
+	_, err := callServer(cniserver.CmdDel, args)
+	return err
+}
+
+// callServer marshals a skel.CmdArgs into a cniserver.Request, posts it to
+// the daemon, and unmarshals the result (nil for DEL/CHECK) or surfaces the
+// server's error as our own.
+func callServer(cmd cniserver.CmdType, args *skel.CmdArgs) (types.Result, error) {
+	req := cniserver.Request{
+		Cmd:         cmd,
+		ContainerID: args.ContainerID,
+		Netns:       args.Netns,
+		IfName:      args.IfName,
+		Args:        args.Args,
+		Path:        args.Path,
+		StdinData:   args.StdinData,
+	}
+
+	body, err := json.Marshal(&req)
+	if err != nil {
+		return nil, fmt.Errorf("cnishim: failed to marshal request: %v", err)
+	}
+
+	httpResp, err := httpClient.Post("http://cni-genie/cmd", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("cnishim: failed to reach cniserver at %s: %v", socketPath, err)
+	}
+	defer httpResp.Body.Close()
+
+	var resp cniserver.Response
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("cnishim: failed to decode cniserver response: %v", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("cnishim: cniserver error: %s", resp.Error)
+	}
+	if len(resp.Result) == 0 {
+		return nil, nil
+	}
+
+	result, err := current.NewResult(resp.Result)
+	if err != nil {
+		return nil, fmt.Errorf("cnishim: failed to parse cniserver result: %v", err)
+	}
+	return result, nil
+}
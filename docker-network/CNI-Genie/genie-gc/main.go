@@ -0,0 +1,171 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Command genie-gc reconciles leaked delegate CNI state. It lists every
+attachment record Genie has persisted under /var/lib/cni-genie, cross-checks
+each containerID against the set of running containers, and invokes
+DelNetworkList (via genie.ReconcileOrphan) for every attachment whose
+container is gone - closing the well-known CNI "DEL never ran, IPAM entry
+leaked" class of bugs (e.g. after a kubelet crash between DEL attempts). It
+also reconciles genie.ResultCacheDir directly: a cached delegate result
+whose container is gone but whose attachment record never made it to disk
+(e.g. the process died between the two writes) would otherwise never be
+found by the attachment-record sweep above.
+
+Running containers are normally listed through the CRI socket; if that
+dial fails (e.g. no dockershim/containerd on this node), genie-gc falls
+back to scanning /proc for container IDs in each process's cgroup path, so
+a GC pass still makes progress on a node where CRI is unavailable.
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/Huawei-PaaS/CNI-Genie/genie"
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+func main() {
+	criSocket := flag.String("cri-socket", "/var/run/dockershim.sock", "path to the CRI socket to cross-check container IDs against")
+	once := flag.Bool("once", false, "run a single reconciliation pass and exit instead of looping")
+	interval := flag.Duration("interval", 5*time.Minute, "how often to reconcile when not run with -once")
+	flag.Parse()
+
+	if err := reconcileOnce(*criSocket); err != nil {
+		fmt.Fprintf(os.Stderr, "genie-gc: reconciliation pass failed: %v\n", err)
+	}
+	if *once {
+		return
+	}
+
+	for range time.Tick(*interval) {
+		if err := reconcileOnce(*criSocket); err != nil {
+			fmt.Fprintf(os.Stderr, "genie-gc: reconciliation pass failed: %v\n", err)
+		}
+	}
+}
+
+// reconcileOnce lists Genie's attachment records and cached delegate
+// results, lists running containers (CRI, falling back to /proc), and
+// reconciles every attachment or cache entry whose container is no longer
+// running.
+func reconcileOnce(criSocket string) error {
+	running, err := listRunningContainerIDs(criSocket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "genie-gc: CRI socket %s unreachable (%v), falling back to /proc\n", criSocket, err)
+		running, err = listRunningContainerIDsFromProc()
+		if err != nil {
+			return fmt.Errorf("failed to list running containers from /proc: %v", err)
+		}
+	}
+
+	records, err := genie.ListAttachments()
+	if err != nil {
+		return fmt.Errorf("failed to list attachment records: %v", err)
+	}
+	reconciled := map[string]bool{}
+	for _, record := range records {
+		if running[record.ContainerID] {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "genie-gc: reconciling orphaned attachment for container %s\n", record.ContainerID)
+		if err := genie.ReconcileOrphan(record); err != nil {
+			fmt.Fprintf(os.Stderr, "genie-gc: failed to reconcile %s: %v\n", record.ContainerID, err)
+			continue
+		}
+		reconciled[record.ContainerID] = true
+	}
+
+	cached, err := genie.ListResultCacheContainerIDs()
+	if err != nil {
+		return fmt.Errorf("failed to list cached results: %v", err)
+	}
+	for _, containerID := range cached {
+		if running[containerID] || reconciled[containerID] {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "genie-gc: reclaiming cached results not cleared by an attachment record for container %s\n", containerID)
+		if err := genie.ReclaimCachedResults(containerID); err != nil {
+			fmt.Fprintf(os.Stderr, "genie-gc: failed to reclaim cached results for %s: %v\n", containerID, err)
+		}
+	}
+	return nil
+}
+
+// listRunningContainerIDs queries the CRI socket for every container it
+// currently knows about, regardless of state, so a container that's
+// stopped-but-not-yet-removed is still treated as "known" rather than
+// immediately torn down out from under the runtime.
+func listRunningContainerIDs(criSocket string) (map[string]bool, error) {
+	conn, err := grpc.Dial(criSocket, grpc.WithInsecure(), grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+		return net.DialTimeout("unix", addr, timeout)
+	}))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client := runtimeapi.NewRuntimeServiceClient(conn)
+	resp, err := client.ListContainers(context.Background(), &runtimeapi.ListContainersRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string]bool{}
+	for _, c := range resp.Containers {
+		ids[c.Id] = true
+	}
+	return ids, nil
+}
+
+// containerIDInCgroup matches the 64-hex-character container ID docker and
+// containerd both embed in a process's cgroup path, e.g.
+// "12:memory:/kubepods/.../docker-<id>.scope" or ".../<id>".
+var containerIDInCgroup = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// listRunningContainerIDsFromProc is the fallback used when the CRI socket
+// can't be reached: it scans every process's /proc/<pid>/cgroup for a
+// container ID, which works against any runtime without needing its own
+// API. It only finds containers that still have a live process, so unlike
+// listRunningContainerIDs it does not count a stopped-but-not-removed
+// container as known.
+func listRunningContainerIDsFromProc() (map[string]bool, error) {
+	procEntries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string]bool{}
+	for _, entry := range procEntries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile("/proc/" + entry.Name() + "/cgroup")
+		if err != nil {
+			continue
+		}
+		for _, id := range containerIDInCgroup.FindAllString(string(data), -1) {
+			ids[id] = true
+		}
+	}
+	return ids, nil
+}
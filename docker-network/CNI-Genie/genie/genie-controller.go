@@ -78,12 +78,56 @@ func ParseCNIConf(confData []byte) (utils.NetConf, error) {
 	return conf, nil
 }
 
+// defaultIfName returns the interface name a delegate gets when its "cni"
+// annotation entry doesn't request one explicitly: the first delegate is
+// the pod's primary interface "eth0", and every one after it gets "netN",
+// mirroring how ocicni names additional attachments for multi-network pods.
+func defaultIfName(i int) string {
+	if i == 0 {
+		return "eth0"
+	}
+	return "net" + strconv.Itoa(i)
+}
+
+// validateUniqueIfNames returns an error if two delegates in annots would
+// end up requesting the same interface name, resolving each delegate's
+// effective name (its own IfName, or defaultIfName(i) when unset) exactly
+// the way AddPodNetworkWithClient's attach loop does.
+func validateUniqueIfNames(annots []utils.PluginInfo) error {
+	usedIfNames := make(map[string]bool, len(annots))
+	for i, pluginElement := range annots {
+		intfName := pluginElement.IfName
+		if intfName == "" {
+			intfName = defaultIfName(i)
+		}
+		if usedIfNames[intfName] {
+			return fmt.Errorf("CNI Genie error: interface name %q is requested by more than one delegate", intfName)
+		}
+		usedIfNames[intfName] = true
+	}
+	return nil
+}
+
 // AddPodNetwork adds pod networking. It has logic to parse each pod
 // definition's annotations. It looks for container networking solutions (CNS)
 // types passed as annotation in pod defintion. For every CNS types, it talks
 // to corresponding CNS object and fetches an IP from it's IPAM.
 // It also applies the IP as ethX inside the pod.
 func AddPodNetwork(cniArgs utils.CNIArgs, conf utils.NetConf) (types.Result, error) {
+	// create kubeclient to talk to k8s api-server
+	kubeClient, err := GetKubeClient(conf)
+	if err != nil {
+		return nil, fmt.Errorf("CNI Genie error at GetKubeClient: %v", err)
+	}
+	return AddPodNetworkWithClient(kubeClient, cniArgs, conf)
+}
+
+// AddPodNetworkWithClient is the same as AddPodNetwork but takes an
+// already-constructed kubeClient instead of building one from conf on every
+// call. This is the entry point used by pkg/cniserver, which keeps a single
+// long-lived clientset around instead of re-reading kubeconfig on every CNI
+// invocation.
+func AddPodNetworkWithClient(kubeClient *kubernetes.Clientset, cniArgs utils.CNIArgs, conf utils.NetConf) (types.Result, error) {
 	// Collect the result in this variable - this is ultimately what gets "returned" by this function by printing
 	// it to stdout.
 	var endResult types.Result
@@ -98,12 +142,6 @@ func AddPodNetwork(cniArgs utils.CNIArgs, conf utils.NetConf) (types.Result, err
 		return nil, fmt.Errorf("CNI Genie internal error at getIdentifiers: %v", err)
 	}
 
-	// create kubeclient to talk to k8s api-server
-	kubeClient, err := GetKubeClient(conf)
-	if err != nil {
-		return nil, fmt.Errorf("CNI Genie error at GetKubeClient: %v", err)
-	}
-
 	// parse pod annotations for cns types
 	// eg:
 	//    cni: "canal,weave"
@@ -113,23 +151,62 @@ func AddPodNetwork(cniArgs utils.CNIArgs, conf utils.NetConf) (types.Result, err
 	}
 
 	multiIPPrefAnnot := MultiIPPreferencesAnnotationFormat
+	defaultRouteAnnot := ParsePodAnnotationsForDefaultRoute(kubeClient, k8sArgs)
+	portMappings, primaryIfName, err := ParsePodAnnotationsForPortMappings(kubeClient, k8sArgs)
+	if err != nil {
+		return nil, fmt.Errorf("CNI Genie error at ParsePodAnnotationsForPortMappings: %v", err)
+	}
+
+	// Validate every delegate's ifname up front, before any of them are
+	// actually attached: catching a collision here means nothing has run
+	// yet, so there's nothing to roll back. Catching it mid-loop instead
+	// would require routing the error through the same
+	// newErr/succeededPlugins rollback path as an addNetwork failure, and
+	// would leak the IPAM allocations of every delegate already attached
+	// in an earlier iteration.
+	if err := validateUniqueIfNames(annots); err != nil {
+		return nil, err
+	}
 
 	var newErr error
 	var intfName string
 	noOfIps := len(annots)
+	attachedPlugins := make([]utils.PluginInfo, 0, len(annots))
+	succeededPlugins := make([]utils.PluginInfo, 0, len(annots))
 	for i, pluginElement := range annots {
 		if pluginElement.IfName != "" {
 			intfName = pluginElement.IfName
 		} else {
-			intfName = "eth" + strconv.Itoa(i)
+			intfName = defaultIfName(i)
+		}
+		if defaultRouteAnnot != "" && (pluginElement.PluginName == defaultRouteAnnot || intfName == defaultRouteAnnot) {
+			// Hint the delegate that it was chosen as the pod's default
+			// route, for the delegates that honor GATEWAY in CNI_ARGS.
+			// The authoritative decision is still made below, once every
+			// delegate's result has been merged.
+			pluginElement.DefaultRoute = true
+		}
+		if len(portMappings) > 0 && intfName == primaryIfName {
+			pluginElement.PortMappings = portMappings
 		}
+		// Record the concrete ifname we're about to use (it may have been
+		// defaulted above) so DEL can replay the exact same RuntimeConf
+		// without re-deriving it from annotations.
+		pluginElement.IfName = intfName
+		attachedPlugins = append(attachedPlugins, pluginElement)
+
 		// fetches an IP from corresponding CNS IPAM and returns result object
 		result, err = addNetwork(intfName, pluginElement, cniArgs)
 		fmt.Fprintf(os.Stderr, "CNI Genie addNetwork err *** %v result***  %v\n", err, result)
 		if err != nil {
 			newErr = err
+		} else {
+			if cacheErr := persistResult(cniArgs.ContainerID, pluginElement.PluginName, intfName, result); cacheErr != nil {
+				fmt.Fprintf(os.Stderr, "CNI Genie failed to cache delegate result for %s/%s: %v\n", pluginElement.PluginName, intfName, cacheErr)
+			}
+			succeededPlugins = append(succeededPlugins, pluginElement)
 		}
-		endResult, err = mergeWithResult(result, endResult)
+		endResult, err = mergeWithResult(result, endResult, pluginElement)
 		if err != nil {
 			newErr = err
 		}
@@ -137,23 +214,113 @@ func AddPodNetwork(cniArgs utils.CNIArgs, conf utils.NetConf) (types.Result, err
 		/* If pod has only one ip it will be shown as part of pod ip hence multi ip preference is not needed*/
 		if noOfIps > 1 {
 			// Update pod definition with IPs "multi-ip-preferences"
-			multiIPPrefAnnot, err = UpdatePodDefinition(intfName, i+1, result, multiIPPrefAnnot, kubeClient, k8sArgs)
+			multiIPPrefAnnot, err = UpdatePodDefinition(intfName, result, multiIPPrefAnnot, kubeClient, k8sArgs)
 			if err != nil {
 				newErr = err
 			}
 		}
 	}
 	if newErr != nil {
+		// Roll back every delegate that already succeeded so a partial
+		// failure never leaks an IPAM allocation the pod will never use.
+		for _, succeeded := range succeededPlugins {
+			if delErr := deleteNetwork(succeeded.IfName, succeeded, cniArgs); delErr != nil {
+				fmt.Fprintf(os.Stderr, "CNI Genie rollback failed to delete network %s on %s: %v\n", succeeded.PluginName, succeeded.IfName, delErr)
+			}
+			if delErr := removeResult(cniArgs.ContainerID, succeeded.PluginName, succeeded.IfName); delErr != nil {
+				fmt.Fprintf(os.Stderr, "CNI Genie rollback failed to remove cached result for %s on %s: %v\n", succeeded.PluginName, succeeded.IfName, delErr)
+			}
+		}
 		return nil, fmt.Errorf("CNI Genie error at addNetwork: %v", newErr)
 	}
+
+	if defaultRouteAnnot != "" {
+		endResult, err = electDefaultRoute(endResult, defaultRouteAnnot, annots)
+		if err != nil {
+			return nil, fmt.Errorf("CNI Genie error at electDefaultRoute: %v", err)
+		}
+	}
+
+	// Persist exactly what we just attached so DEL is idempotent even if
+	// the pod's annotations change or the apiserver becomes unreachable
+	// before the container is torn down.
+	if err := persistAttachment(AttachmentRecord{
+		ContainerID: cniArgs.ContainerID,
+		Netns:       cniArgs.Netns,
+		Plugins:     attachedPlugins,
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "CNI Genie failed to persist attachment record: %v\n", err)
+	}
+
 	return endResult, nil
 }
 
+// CheckPodNetwork verifies pod networking for every delegate CNI Genie
+// attached at ADD time. Unlike AddPodNetwork/DeletePodNetwork it never
+// consults pod annotations: CHECK must confirm what's actually in place, so
+// it always replays the attachment record persisted by AddPodNetworkWithClient.
+// It returns an error if no attachment record exists, or if any delegate
+// reports an inconsistency.
+func CheckPodNetwork(cniArgs utils.CNIArgs) error {
+	record, err := loadAttachment(cniArgs.ContainerID)
+	if err != nil {
+		return fmt.Errorf("CNI Genie error at loadAttachment: %v", err)
+	}
+	if record == nil {
+		return fmt.Errorf("CNI Genie no attachment record found for container %s", cniArgs.ContainerID)
+	}
+
+	var newErr error
+	for _, pluginInfo := range record.Plugins {
+		if err := checkNetwork(pluginInfo.IfName, pluginInfo, cniArgs); err != nil {
+			fmt.Fprintf(os.Stderr, "CNI Genie Error checkNetwork %v", err)
+			newErr = err
+		}
+	}
+	if newErr != nil {
+		return fmt.Errorf("CNI Genie error at checkNetwork: %v", newErr)
+	}
+	return nil
+}
+
 // DeletePodNetwork deletes pod networking. It has logic to parse each pod
 // definition's annotations. It looks for container networking solutions (CNS)
 // types passed as annotation in pod defintion. For every CNS types, it talks
 // to corresponding CNS object and releases an IP from it's IPAM.
 func DeletePodNetwork(cniArgs utils.CNIArgs, conf utils.NetConf) error {
+	// create kubeclient to talk to k8s api-server
+	kubeClient, err := GetKubeClient(conf)
+	if err != nil {
+		return fmt.Errorf("CNI Genie error at GetKubeClient: %v", err)
+	}
+	return DeletePodNetworkWithClient(kubeClient, cniArgs, conf)
+}
+
+// DeletePodNetworkWithClient is the same as DeletePodNetwork but takes an
+// already-constructed kubeClient, see AddPodNetworkWithClient.
+func DeletePodNetworkWithClient(kubeClient *kubernetes.Clientset, cniArgs utils.CNIArgs, conf utils.NetConf) error {
+	// DEL must be idempotent: prefer the attachment record written by ADD,
+	// which replays the exact delegates/ifnames used even if the pod's
+	// annotations have since changed or the apiserver is unreachable.
+	record, err := loadAttachment(cniArgs.ContainerID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "CNI Genie failed to read attachment record, falling back to annotations: %v\n", err)
+	}
+	if record != nil {
+		// Unlike ReconcileOrphan (used by the GC reconciler, which has no
+		// live skel.CmdArgs to work with), this is a real DEL invocation:
+		// pass cniArgs through as-is so delegates that need CNI_ARGS or
+		// stdin on DEL (e.g. calico) get the same input they got on ADD,
+		// not just the bare ContainerID/Netns the record retained.
+		if err := deleteRecordedPlugins(*record, cniArgs); err != nil {
+			return fmt.Errorf("CNI Genie error at deleteNetwork: %v", err)
+		}
+		if err := removeResultsForContainer(cniArgs.ContainerID); err != nil {
+			fmt.Fprintf(os.Stderr, "CNI Genie failed to prune cached results for %s: %v\n", cniArgs.ContainerID, err)
+		}
+		return removeAttachment(cniArgs.ContainerID)
+	}
+
 	k8sArgs, err := loadArgs(cniArgs)
 	if err != nil {
 		return fmt.Errorf("CNI Genie internal error at loadArgs: %v", err)
@@ -163,12 +330,6 @@ func DeletePodNetwork(cniArgs utils.CNIArgs, conf utils.NetConf) error {
 		return fmt.Errorf("CNI Genie internal error at getIdentifiers: %v", err)
 	}
 
-	// create kubeclient to talk to k8s api-server
-	kubeClient, err := GetKubeClient(conf)
-	if err != nil {
-		return fmt.Errorf("CNI Genie error at GetKubeClient: %v", err)
-	}
-
 	// parse pod annotations for cns types
 	// eg:
 	//    cni: "canal,weave"
@@ -183,10 +344,10 @@ func DeletePodNetwork(cniArgs utils.CNIArgs, conf utils.NetConf) error {
 		if pluginElement.IfName != "" {
 			intfName = pluginElement.IfName
 		} else {
-			intfName = "eth" + strconv.Itoa(i)
+			intfName = defaultIfName(i)
 		}
 		// releases an IP from corresponding CNS IPAM and returns error if any exception
-		err = deleteNetwork(intfName, pluginElement.PluginName, cniArgs)
+		err = deleteNetwork(intfName, pluginElement, cniArgs)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "CNI Genie Error deleteNetwork %v", err)
 			newErr = err
@@ -195,7 +356,50 @@ func DeletePodNetwork(cniArgs utils.CNIArgs, conf utils.NetConf) error {
 	if newErr != nil {
 		return fmt.Errorf("CNI Genie error at deleteNetwork: %v", newErr)
 	}
-	return nil
+	if err := removeResultsForContainer(cniArgs.ContainerID); err != nil {
+		fmt.Fprintf(os.Stderr, "CNI Genie failed to prune cached results for %s: %v\n", cniArgs.ContainerID, err)
+	}
+	return removeAttachment(cniArgs.ContainerID)
+}
+
+// mergeMultiIPPreferences folds one delegate's result into an
+// already-in-progress MultiIPPreferences, initializing its maps on first
+// use. A single delegate can itself return more than one IP per interface
+// (e.g. dual-stack IPv4+IPv6), so every entry in currResult.IPs is recorded
+// rather than just the first one, grouped by the interface it was assigned
+// to. Ips is keyed by the running MultiEntry total across every
+// delegate/IP processed so far (multiIPPrefAnnot round-trips through each
+// delegate's call), not by a per-delegate index: two delegates, or one
+// dual-stack delegate, would otherwise reuse the same "ipN" key and
+// overwrite each other's entry.
+func mergeMultiIPPreferences(prefs utils.MultiIPPreferences, intfName string, currResult *current.Result) utils.MultiIPPreferences {
+	if prefs.Ips == nil {
+		prefs.Ips = map[string]utils.IPAddressPreferences{}
+	}
+	if prefs.IfaceIPs == nil {
+		prefs.IfaceIPs = map[string][]utils.IPAddressPreferences{}
+	}
+
+	for _, ip := range currResult.IPs {
+		ifaceName := intfName
+		if ip.Interface >= 0 && ip.Interface < len(currResult.Interfaces) {
+			ifaceName = currResult.Interfaces[ip.Interface].Name
+		}
+		var gw string
+		if ip.Gateway != nil {
+			gw = ip.Gateway.String()
+		}
+		pref := utils.IPAddressPreferences{
+			IP:        ip.Address.IP.String(),
+			Version:   ip.Version,
+			Gateway:   gw,
+			Interface: ifaceName,
+		}
+		prefs.MultiEntry = prefs.MultiEntry + 1
+		prefs.Ips[fmt.Sprintf("ip%d", prefs.MultiEntry)] = pref
+		prefs.IfaceIPs[ifaceName] = append(prefs.IfaceIPs[ifaceName], pref)
+	}
+	return prefs
 }
 
 // UpdatePodDefinition updates the pod definition with multi ip addresses.
@@ -203,7 +407,7 @@ func DeletePodNetwork(cniArgs utils.CNIArgs, conf utils.NetConf) error {
 // different configured networking solutions. It is also used in "nocni"
 // case where ideal network has been chosen for the pod. Pod annotation
 // in this case will update with CNS that's chosen at run time.
-func UpdatePodDefinition(intfName string, ipIndex int, result types.Result, multiIPPrefAnnot string, client *kubernetes.Clientset, k8sArgs utils.K8sArgs) (string, error) {
+func UpdatePodDefinition(intfName string, result types.Result, multiIPPrefAnnot string, client *kubernetes.Clientset, k8sArgs utils.K8sArgs) (string, error) {
 	var multiIPPreferences utils.MultiIPPreferences
 
 	if err := json.Unmarshal([]byte(multiIPPrefAnnot), &multiIPPreferences); err != nil {
@@ -215,9 +419,24 @@ func UpdatePodDefinition(intfName string, ipIndex int, result types.Result, mult
 		return multiIPPrefAnnot, fmt.Errorf("CNI Genie Error when converting result to current version = %s", err)
 	}
 
-	multiIPPreferences.MultiEntry = multiIPPreferences.MultiEntry + 1
-	multiIPPreferences.Ips["ip"+strconv.Itoa(ipIndex)] =
-		utils.IPAddressPreferences{currResult.IPs[0].Address.IP.String(), intfName}
+	multiIPPreferences = mergeMultiIPPreferences(multiIPPreferences, intfName, currResult)
+
+	for _, route := range currResult.Routes {
+		var gw string
+		if route.GW != nil {
+			gw = route.GW.String()
+		}
+		multiIPPreferences.Routes = append(multiIPPreferences.Routes, utils.RoutePreference{
+			Dst: route.Dst.String(),
+			GW:  gw,
+		})
+	}
+	multiIPPreferences.DNS.Nameservers = append(multiIPPreferences.DNS.Nameservers, currResult.DNS.Nameservers...)
+	multiIPPreferences.DNS.Search = append(multiIPPreferences.DNS.Search, currResult.DNS.Search...)
+	multiIPPreferences.DNS.Options = append(multiIPPreferences.DNS.Options, currResult.DNS.Options...)
+	if currResult.DNS.Domain != "" {
+		multiIPPreferences.DNS.Domain = currResult.DNS.Domain
+	}
 
 	tmpMultiIPPreferences, err := json.Marshal(&multiIPPreferences)
 
@@ -302,10 +521,10 @@ func GetKubeClient(conf utils.NetConf) (*kubernetes.Clientset, error) {
 	return kubernetes.NewForConfig(config)
 }
 
-//ParsePodAnnotationsForCNI does following tasks
-//  - get pod definition
-//  - parses annotation section for "cni"
-//  - Returns string array of networking solutions
+// ParsePodAnnotationsForCNI does following tasks
+//   - get pod definition
+//   - parses annotation section for "cni"
+//   - Returns string array of networking solutions
 func ParsePodAnnotationsForCNI(client *kubernetes.Clientset, k8sArgs utils.K8sArgs, conf utils.NetConf) ([]utils.PluginInfo, error) {
 	var annots []utils.PluginInfo
 
@@ -356,7 +575,53 @@ func ParsePodAnnotationsForNetworks(client *kubernetes.Clientset, k8sArgs utils.
 	return networks
 }
 
-//  parseCNIAnnotations parses pod yaml defintion for "cni" annotations.
+// ParsePodAnnotationsForDefaultRoute does following tasks
+//   - get pod definition
+//   - parses annotation section for "default-route"
+//   - Returns string, either a plugin name (e.g. "weave") or an interface
+//     name (e.g. "eth1"); empty if the pod has no preference and every
+//     delegate's default route should be left as merged.
+func ParsePodAnnotationsForDefaultRoute(client *kubernetes.Clientset, k8sArgs utils.K8sArgs) string {
+	annot, _ := getK8sPodAnnotations(client, k8sArgs)
+	return strings.TrimSpace(annot["default-route"])
+}
+
+// PrimaryInterfaceAnnotation names the annotation used to pick which
+// delegate is the pod's "primary" interface, i.e. the one the portMappings
+// capability (hostPort) is attached to. Defaults to "eth0".
+const PrimaryInterfaceAnnotation = "cni.genie/primary-interface"
+
+// PortMappingsAnnotation carries the CNI portMappings runtime capability
+// argument as a JSON list of {hostPort, containerPort, protocol, hostIP}.
+const PortMappingsAnnotation = "cni.genie/port-mappings"
+
+// ParsePodAnnotationsForPortMappings does following tasks
+//   - get pod definition
+//   - parses annotation section for "cni.genie/port-mappings" and
+//     "cni.genie/primary-interface"
+//   - Returns the requested port mappings and the interface name ("eth0" if
+//     unset) they should be attached to
+func ParsePodAnnotationsForPortMappings(client *kubernetes.Clientset, k8sArgs utils.K8sArgs) ([]utils.PortMapping, string, error) {
+	annot, _ := getK8sPodAnnotations(client, k8sArgs)
+
+	primaryIfName := strings.TrimSpace(annot[PrimaryInterfaceAnnotation])
+	if primaryIfName == "" {
+		primaryIfName = "eth0"
+	}
+
+	raw := strings.TrimSpace(annot[PortMappingsAnnotation])
+	if raw == "" {
+		return nil, primaryIfName, nil
+	}
+
+	var portMappings []utils.PortMapping
+	if err := json.Unmarshal([]byte(raw), &portMappings); err != nil {
+		return nil, primaryIfName, fmt.Errorf("CNI Genie failed to parse %s annotation: %v", PortMappingsAnnotation, err)
+	}
+	return portMappings, primaryIfName, nil
+}
+
+// parseCNIAnnotations parses pod yaml defintion for "cni" annotations.
 func parseCNIAnnotations(annot map[string]string, client *kubernetes.Clientset, k8sArgs utils.K8sArgs, conf utils.NetConf) ([]utils.PluginInfo, error) {
 	var finalPluginInfos []utils.PluginInfo
 	var pluginInfo utils.PluginInfo
@@ -372,12 +637,18 @@ func parseCNIAnnotations(annot map[string]string, client *kubernetes.Clientset,
 			finalPluginInfos = append(finalPluginInfos, pluginInfo)
 			pluginInfo = utils.PluginInfo{}
 		}
-	} else if strings.TrimSpace(annot["cni"]) != "" {
-		cniAnnots := strings.Split(annot["cni"], ",")
-		for _, pluginName := range cniAnnots {
-			pluginInfo.PluginName = pluginName
-			finalPluginInfos = append(finalPluginInfos, pluginInfo)
-			pluginInfo = utils.PluginInfo{}
+	} else if raw := strings.TrimSpace(annot["cni"]); raw != "" {
+		if strings.HasPrefix(raw, "[") {
+			var err error
+			finalPluginInfos, err = parseStructuredCNIAnnotation(raw)
+			if err != nil {
+				return finalPluginInfos, fmt.Errorf("CNI Genie error parsing structured cni annotation: %v", err)
+			}
+		} else {
+			cniAnnots := strings.Split(raw, ",")
+			for _, cniAnnot := range cniAnnots {
+				finalPluginInfos = append(finalPluginInfos, parseCNIAnnotEntry(cniAnnot))
+			}
 		}
 
 		fmt.Fprintf(os.Stderr, "CNI Genie finalPluginInfos= %v\n", finalPluginInfos)
@@ -386,6 +657,20 @@ func parseCNIAnnotations(annot map[string]string, client *kubernetes.Clientset,
 
 		var err error
 
+		if isNADAnnotation(networksAnnot) {
+			fmt.Fprintf(os.Stderr, "CNI Genie networks annotation looks like a NetworkAttachmentDefinition selector, resolving via NAD client\n")
+			nadClient, err := GetNADClient(conf)
+			if err != nil {
+				return finalPluginInfos, fmt.Errorf("CNI Genie GetNADClient err= %v\n", err)
+			}
+			finalPluginInfos, err = GetPluginInfoFromNAD(strings.TrimSpace(networksAnnot), string(k8sArgs.K8S_POD_NAMESPACE), nadClient)
+			if err != nil {
+				return finalPluginInfos, fmt.Errorf("CNI Genie GetPluginInfoFromNAD err= %v\n", err)
+			}
+			fmt.Fprintf(os.Stderr, "CNI Genie return finalPluginInfos = %v\n", finalPluginInfos)
+			return finalPluginInfos, nil
+		}
+
 		finalPluginInfos, err = GetPluginInfoFromNwAnnot(strings.TrimSpace(annot["networks"]), string(k8sArgs.K8S_POD_NAMESPACE), client)
 		if err != nil {
 			return finalPluginInfos, fmt.Errorf("CNI Genie GetPluginInfoFromNwAnnot err= %v\n", err)
@@ -420,6 +705,73 @@ func parseCNIAnnotations(annot map[string]string, client *kubernetes.Clientset,
 	return finalPluginInfos, nil
 }
 
+// parseCNIAnnotEntry parses a single entry of the "cni" annotation's
+// extended syntax, e.g. "weave;ip=10.0.0.5;mac=aa:bb:cc:dd:ee:ff;gateway=true",
+// into a utils.PluginInfo. The plugin name is always the first
+// semicolon-separated field; unrecognized keys are ignored so the syntax
+// stays forward-compatible.
+func parseCNIAnnotEntry(entry string) utils.PluginInfo {
+	fields := strings.Split(entry, ";")
+	pluginInfo := utils.PluginInfo{PluginName: strings.TrimSpace(fields[0])}
+
+	for _, field := range fields[1:] {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		switch strings.ToLower(key) {
+		case "ip":
+			pluginInfo.IPs = strings.Split(value, ",")
+		case "mac":
+			pluginInfo.Mac = value
+		case "gateway", "default-route":
+			pluginInfo.DefaultRoute = value == "" || value == "true"
+		}
+	}
+	return pluginInfo
+}
+
+// structuredCNIEntry is the per-network object accepted by the "cni"
+// annotation's JSON form, e.g.
+//
+//	[{"name":"weave","interface":"net1","ips":["10.1.2.3/24"],
+//	  "mac":"aa:bb:cc:dd:ee:ff","args":{"foo":"bar"}}]
+//
+// It exists alongside the legacy comma-separated and ";key=value" string
+// forms (parseCNIAnnotEntry) so existing pod specs keep working untouched,
+// giving the same per-network expressiveness as Multus/ocicni's
+// NetAttachment without requiring a NetworkAttachmentDefinition.
+type structuredCNIEntry struct {
+	Name      string            `json:"name"`
+	Interface string            `json:"interface,omitempty"`
+	IPs       []string          `json:"ips,omitempty"`
+	Mac       string            `json:"mac,omitempty"`
+	Args      map[string]string `json:"args,omitempty"`
+}
+
+// parseStructuredCNIAnnotation parses the JSON-list form of the "cni"
+// annotation into the same []utils.PluginInfo the rest of the delegation
+// pipeline already consumes.
+func parseStructuredCNIAnnotation(raw string) ([]utils.PluginInfo, error) {
+	var entries []structuredCNIEntry
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, err
+	}
+
+	pluginInfos := make([]utils.PluginInfo, 0, len(entries))
+	for _, entry := range entries {
+		pluginInfos = append(pluginInfos, utils.PluginInfo{
+			PluginName: entry.Name,
+			IfName:     entry.Interface,
+			IPs:        entry.IPs,
+			Mac:        entry.Mac,
+			Args:       entry.Args,
+		})
+	}
+	return pluginInfos, nil
+}
+
 func ParseCNIConfFromFile(filename string) (*libcni.NetworkConfigList, error) {
 	var err error
 	var confList *libcni.NetworkConfigList
@@ -554,15 +906,49 @@ func useCustomSubnet(confdata []byte, subnet string) ([]byte, error) {
 	return confbytes, nil
 }
 
-// addNetwork is a core function that delegates call to pull IP from a Container Networking Solution (CNI Plugin)
-func addNetwork(intfName string, pluginInfo utils.PluginInfo, cniArgs utils.CNIArgs) (types.Result, error) {
-	var result types.Result
-	var err error
+// injectPrevResult stamps a delegate's conf bytes with the result cached
+// for it at ADD time (see resultcache.go), the same way libcni itself
+// threads prevResult through a chained plugin list, so a delegate that
+// inspects prevResult on DEL/CHECK sees the same picture it would have if
+// libcni had called it directly.
+func injectPrevResult(confBytes []byte, prevResult *current.Result) ([]byte, error) {
+	conf := make(map[string]interface{})
+	if err := json.Unmarshal(confBytes, &conf); err != nil {
+		return nil, fmt.Errorf("Error Unmarshalling confdata: %v", err)
+	}
+	conf["prevResult"] = prevResult
+	confbytes, err := json.Marshal(&conf)
+	if err != nil {
+		return nil, fmt.Errorf("Error Marshalling confdata: %v", err)
+	}
+	return confbytes, nil
+}
 
-	cniName := pluginInfo.PluginName
-	fmt.Fprintf(os.Stderr, "CNI Genie cniName=%v intfName =%v\n", cniName, intfName)
+// resolveDelegateConf finds (or, for Genie's bundled plugins, creates) the
+// libcni.NetworkConfigList for a delegate, applying any custom subnet
+// requested via pluginInfo.Subnet. Shared by addNetwork and checkNetwork so
+// ADD and CHECK always resolve a delegate's conf the same way.
+func resolveDelegateConf(pluginInfo utils.PluginInfo) (*libcni.NetworkConfigList, error) {
+	if len(pluginInfo.RawConfig) > 0 {
+		// This delegate came from a NAD that embeds its own config: use it
+		// directly rather than name-matching a conf file under
+		// DefaultNetDir, which may not exist or may have drifted from what
+		// the NAD actually specifies.
+		netConfigList, err := libcni.ConfListFromBytes(pluginInfo.RawConfig)
+		if err != nil {
+			return nil, fmt.Errorf("CNI Genie failed to parse embedded NAD config for %s: %v", pluginInfo.PluginName, err)
+		}
+		if pluginInfo.Subnet != "" {
+			confbytes, err := useCustomSubnet(netConfigList.Plugins[0].Bytes, pluginInfo.Subnet)
+			if err != nil {
+				return nil, fmt.Errorf("Error while inserting custom subnet into plugin configuration: %v", err)
+			}
+			netConfigList.Plugins[0].Bytes = confbytes
+		}
+		return netConfigList, nil
+	}
 
-	cniConfig := libcni.CNIConfig{Path: []string{DefaultPluginDir}}
+	cniName := pluginInfo.PluginName
 
 	files, err := libcni.ConfFiles(DefaultNetDir, []string{".conf", ".conflist"})
 	fmt.Fprintf(os.Stderr, "CNI Genie files =%v\n", files)
@@ -608,17 +994,31 @@ func addNetwork(intfName string, pluginInfo utils.PluginInfo, cniArgs utils.CNIA
 	}
 
 	fmt.Fprintf(os.Stderr, "CNI Genie cni type= %s\n", cniType)
+	return netConfigList, nil
+}
+
+// addNetwork is a core function that delegates call to pull IP from a Container Networking Solution (CNI Plugin)
+func addNetwork(intfName string, pluginInfo utils.PluginInfo, cniArgs utils.CNIArgs) (types.Result, error) {
+	fmt.Fprintf(os.Stderr, "CNI Genie cniName=%v intfName =%v\n", pluginInfo.PluginName, intfName)
+
+	cniConfig := libcni.CNIConfig{Path: []string{DefaultPluginDir}}
+
+	netConfigList, err := resolveDelegateConf(pluginInfo)
+	if err != nil {
+		return nil, err
+	}
+
 	err = os.Unsetenv("CNI_IFNAME")
 	if err != nil {
 		fmt.Errorf("CNI Genie Error while unsetting env variable CNI_IFNAME: %v\n", err)
 	}
-	rtConf, err := runtimeConf(cniArgs, intfName)
+	rtConf, err := runtimeConf(cniArgs, intfName, pluginInfo)
 	if err != nil {
 		return nil, fmt.Errorf("CNI Genie couldn't convert cniArgs to RuntimeConf: %v\n", err)
 	}
 	fmt.Fprintf(os.Stderr, "CNI Genie runtime configuration = %+v\n", rtConf)
 
-	result, err = cniConfig.AddNetworkList(netConfigList, rtConf)
+	result, err := cniConfig.AddNetworkList(netConfigList, rtConf)
 	if err != nil {
 		return nil, err
 	}
@@ -627,45 +1027,63 @@ func addNetwork(intfName string, pluginInfo utils.PluginInfo, cniArgs utils.CNIA
 	return result, nil
 }
 
-// deleteNetwork is a core function that delegates call to release IP from a Container Networking Solution (CNI Plugin)
-func deleteNetwork(intfName string, cniName string, cniArgs utils.CNIArgs) error {
-	var conf *libcni.NetworkConfigList
+// checkNetwork is a core function that delegates a CNI CHECK call to a
+// Container Networking Solution (CNI Plugin), so genie.CheckPodNetwork
+// can confirm a delegate's attachment is still healthy without touching IPAM.
+func checkNetwork(intfName string, pluginInfo utils.PluginInfo, cniArgs utils.CNIArgs) error {
+	fmt.Fprintf(os.Stderr, "CNI Genie check cniName=%v intfName =%v\n", pluginInfo.PluginName, intfName)
 
 	cniConfig := libcni.CNIConfig{Path: []string{DefaultPluginDir}}
 
-	files, err := libcni.ConfFiles(DefaultNetDir, []string{".conf"})
-	fmt.Fprintf(os.Stderr, "CNI Genie files =%v\n", files)
-	switch {
-	case err != nil:
+	netConfigList, err := resolveDelegateConf(pluginInfo)
+	if err != nil {
 		return err
-	case len(files) == 0:
-		return fmt.Errorf("No networks found in %s", DefaultNetDir)
 	}
-	sort.Strings(files)
-	for _, confFile := range files {
-		if strings.Contains(confFile, cniName) && cniName != "" {
-			confFromFile, err := ParseCNIConfFromFile(confFile)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "CNI Genie Error loading CNI config file =%v\n", confFile, err)
-				continue
-			}
-			fmt.Fprintf(os.Stderr, "CNI Genie cniName file found!!!!!! confFromFile.Type =%v\n", confFromFile.Plugins[0].Network.Type)
 
-			conf = confFromFile
-			fmt.Fprintf(os.Stderr, "CNI Genie cni type= %s\n", conf.Plugins[0].Network.Type)
-			rtConf, err := runtimeConf(cniArgs, intfName)
-			if err != nil {
-				return fmt.Errorf("CNI Genie couldn't convert cniArgs to RuntimeConf: %v\n", err)
-			}
-			err = cniConfig.DelNetworkList(conf, rtConf)
-			if err != nil {
-				return err
-			}
-			break
+	rtConf, err := runtimeConf(cniArgs, intfName, pluginInfo)
+	if err != nil {
+		return fmt.Errorf("CNI Genie couldn't convert cniArgs to RuntimeConf: %v\n", err)
+	}
+
+	return cniConfig.CheckNetworkList(netConfigList, rtConf)
+}
+
+// deleteNetwork is a core function that delegates call to release IP from a Container Networking Solution (CNI Plugin)
+func deleteNetwork(intfName string, pluginInfo utils.PluginInfo, cniArgs utils.CNIArgs) error {
+	cniConfig := libcni.CNIConfig{Path: []string{DefaultPluginDir}}
+
+	// Resolve the delegate's conf exactly the way addNetwork/checkNetwork
+	// do, including the RawConfig case: a NAD delegate whose config is
+	// embedded has no match under DefaultNetDir, so falling back to the
+	// file-matching loop here would silently no-op the DEL and leak its
+	// IPAM allocation.
+	netConfigList, err := resolveDelegateConf(pluginInfo)
+	if err != nil {
+		return err
+	}
+
+	// Feed back whatever this delegate returned at ADD time as prevResult,
+	// the same way libcni does for a chained plugin, so a delegate that
+	// looks at prevResult to find the interface/IPs it allocated (rather
+	// than re-deriving them from the netns) tears down the right thing on
+	// DEL. Not every delegate has a cached result (AddPodNetworkWithClient
+	// only logs a persistResult failure), so a miss here is not an error.
+	if prevResult, err := loadResult(cniArgs.ContainerID, pluginInfo.PluginName, intfName); err != nil {
+		fmt.Fprintf(os.Stderr, "CNI Genie failed to load cached result for %s/%s: %v\n", pluginInfo.PluginName, intfName, err)
+	} else if prevResult != nil && len(netConfigList.Plugins) > 0 {
+		lastPlugin := netConfigList.Plugins[len(netConfigList.Plugins)-1]
+		confBytes, err := injectPrevResult(lastPlugin.Bytes, prevResult)
+		if err != nil {
+			return fmt.Errorf("CNI Genie failed to inject cached result into delegate conf for %s/%s: %v", pluginInfo.PluginName, intfName, err)
 		}
+		lastPlugin.Bytes = confBytes
 	}
 
-	return nil
+	rtConf, err := runtimeConf(cniArgs, intfName, pluginInfo)
+	if err != nil {
+		return fmt.Errorf("CNI Genie couldn't convert cniArgs to RuntimeConf: %v\n", err)
+	}
+	return cniConfig.DelNetworkList(netConfigList, rtConf)
 }
 
 func loadArgs(cniArgs utils.CNIArgs) (utils.K8sArgs, error) {
@@ -700,7 +1118,14 @@ func getK8sPodAnnotations(client *kubernetes.Clientset, k8sArgs utils.K8sArgs) (
 	return pod.Annotations, nil
 }
 
-func runtimeConf(cniArgs utils.CNIArgs, iface string) (*libcni.RuntimeConf, error) {
+// runtimeConf builds the libcni.RuntimeConf for a single delegate call.
+// ADD, CHECK and DEL all pass the delegate's pluginInfo (on DEL it may
+// carry only PluginName/IfName, e.g. when rebuilt from a cache filename by
+// ReclaimCachedResults); when it requests a static IP, MAC address or default-route
+// preference those are translated into CNI_ARGS key/value pairs plus a
+// capability RuntimeConfig block, matching how multus/ocicni pass static
+// addressing to delegates.
+func runtimeConf(cniArgs utils.CNIArgs, iface string, pluginInfo utils.PluginInfo) (*libcni.RuntimeConf, error) {
 	k8sArgs, err := loadArgs(cniArgs)
 	if err != nil {
 		return nil, err
@@ -719,11 +1144,51 @@ func runtimeConf(cniArgs utils.CNIArgs, iface string) (*libcni.RuntimeConf, erro
 		args = append(args, [2]string{"K8S_POD_INFRA_CONTAINER_ID", string(k8sArgs.K8S_POD_INFRA_CONTAINER_ID)})
 	}
 
+	capabilityArgs := map[string]interface{}{}
+	if len(pluginInfo.IPs) > 0 {
+		args = append(args, [2]string{"IP", strings.Join(pluginInfo.IPs, ",")})
+	}
+	if pluginInfo.Mac != "" {
+		args = append(args, [2]string{"MAC", pluginInfo.Mac})
+	}
+	if pluginInfo.DefaultRoute {
+		args = append(args, [2]string{"GATEWAY", "true"})
+	}
+	if len(pluginInfo.Args) > 0 {
+		keys := make([]string, 0, len(pluginInfo.Args))
+		for k := range pluginInfo.Args {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			args = append(args, [2]string{k, pluginInfo.Args[k]})
+		}
+	}
+	// libcni only injects a CapabilityArgs entry into a delegate's
+	// RuntimeConfig when the delegate's own conf declares that exact
+	// capability name, so these must be keyed "ips"/"mac" - not wrapped
+	// under an arbitrary "cni.RuntimeConfig" key, which no delegate's
+	// capabilities ever declare and which libcni ignores - the same way
+	// "portMappings" is keyed below.
+	if len(pluginInfo.IPs) > 0 {
+		capabilityArgs["ips"] = pluginInfo.IPs
+	}
+	if pluginInfo.Mac != "" {
+		capabilityArgs["mac"] = pluginInfo.Mac
+	}
+	if len(pluginInfo.PortMappings) > 0 {
+		capabilityArgs["portMappings"] = pluginInfo.PortMappings
+	}
+	if len(capabilityArgs) == 0 {
+		capabilityArgs = nil
+	}
+
 	return &libcni.RuntimeConf{
-		ContainerID: cniArgs.ContainerID,
-		NetNS:       cniArgs.Netns,
-		IfName:      iface,
-		Args:        args}, nil
+		ContainerID:    cniArgs.ContainerID,
+		NetNS:          cniArgs.Netns,
+		IfName:         iface,
+		Args:           args,
+		CapabilityArgs: capabilityArgs}, nil
 }
 
 func defaultPlugins(conf utils.NetConf) []string {
@@ -733,7 +1198,7 @@ func defaultPlugins(conf utils.NetConf) []string {
 	return strings.Split(conf.DefaultPlugin, ",")
 }
 
-func mergeWithResult(srcObj, dstObj types.Result) (types.Result, error) {
+func mergeWithResult(srcObj, dstObj types.Result, requested utils.PluginInfo) (types.Result, error) {
 	srcObj, err := updateRoutes(srcObj)
 	if err != nil {
 		return nil, fmt.Errorf("Routes update failed: %v", err)
@@ -742,6 +1207,9 @@ func mergeWithResult(srcObj, dstObj types.Result) (types.Result, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Failed to fix interfaces: %v", err)
 	}
+	if err := validateRequestedAddressing(requested, srcObj); err != nil {
+		return nil, err
+	}
 
 	if dstObj == nil {
 		return srcObj, nil
@@ -783,6 +1251,69 @@ func mergeWithResult(srcObj, dstObj types.Result) (types.Result, error) {
 	return dst, nil
 }
 
+// validateRequestedAddressing checks that a delegate actually honored the
+// IP/MAC a pod requested of it (e.g. via the structured "cni" annotation's
+// "ips"/"mac" fields). Most CNI plugins only support a static address as a
+// hint, so a silent mismatch here would otherwise surface much later as a
+// confusing connectivity failure instead of at ADD time.
+func validateRequestedAddressing(requested utils.PluginInfo, srcObj types.Result) error {
+	if len(requested.IPs) == 0 && requested.Mac == "" {
+		return nil
+	}
+	src, err := current.NewResultFromResult(srcObj)
+	if err != nil {
+		return fmt.Errorf("CNI Genie couldn't convert delegate result to current version: %v", err)
+	}
+
+	if len(requested.IPs) > 0 {
+		got := make([]string, 0, len(src.IPs))
+		gotSet := map[string]bool{}
+		for _, ip := range src.IPs {
+			got = append(got, ip.Address.String())
+			gotSet[ip.Address.IP.String()] = true
+		}
+		for _, want := range requested.IPs {
+			wantIP, err := parseRequestedIP(want)
+			if err != nil {
+				return fmt.Errorf("CNI Genie couldn't parse requested IP %q for delegate %q: %v", want, requested.PluginName, err)
+			}
+			if !gotSet[wantIP.String()] {
+				return fmt.Errorf("CNI Genie delegate %q did not assign requested IP %s (got %v)", requested.PluginName, want, got)
+			}
+		}
+	}
+
+	if requested.Mac != "" {
+		found := false
+		for _, iface := range src.Interfaces {
+			if strings.EqualFold(iface.Mac, requested.Mac) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("CNI Genie delegate %q did not assign requested MAC %s", requested.PluginName, requested.Mac)
+		}
+	}
+	return nil
+}
+
+// parseRequestedIP parses a requested IP that may or may not carry a
+// prefix length - both the structured "cni" annotation's "ips" field and
+// the legacy "cni: weave;ip=10.0.0.5" syntax allow a bare address - and
+// returns just the address, so callers can compare it against a delegate's
+// returned IP without the mask (which the request doesn't control) causing
+// a spurious mismatch.
+func parseRequestedIP(want string) (net.IP, error) {
+	if ip, _, err := net.ParseCIDR(want); err == nil {
+		return ip, nil
+	}
+	if ip := net.ParseIP(want); ip != nil {
+		return ip, nil
+	}
+	return nil, fmt.Errorf("%q is not a valid IP address or CIDR", want)
+}
+
 // updateRoutes changes nil gateway set in a route to a gateway from IPConfig
 // nil gw in route means default gw from result. When merging results from
 // many results default gw may be set from another CNI network. This may lead to
@@ -830,3 +1361,77 @@ func fixInterfaces(rObj types.Result) (types.Result, error) {
 	}
 	return result, nil
 }
+
+// isDefaultRoute reports whether route is an IPv4 or IPv6 default route
+// (0.0.0.0/0 or ::/0).
+func isDefaultRoute(route *types.Route) bool {
+	ones, bits := route.Dst.Mask.Size()
+	return ones == 0 && (bits == 32 || bits == 128)
+}
+
+// routeOwnerInterface finds the interface index a route belongs to, by
+// matching the route's gateway against the gateway of one of that
+// interface's IPs. Routes from Genie's own delegates always have their GW
+// filled in by updateRoutes before being merged, so this is reliable for
+// routes Genie produced; it returns -1 (no opinion) for anything else.
+func routeOwnerInterface(result *current.Result, route *types.Route) int {
+	if route.GW == nil {
+		return -1
+	}
+	for _, ip := range result.IPs {
+		if ip.Gateway != nil && ip.Gateway.Equal(route.GW) {
+			return ip.Interface
+		}
+	}
+	return -1
+}
+
+// electDefaultRoute implements the "default-route" annotation: after every
+// delegate's result has been merged, it rewrites result.Routes so only the
+// chosen interface (selected by plugin name or interface name) keeps its
+// default route, stripping the default route of every other interface so
+// the kernel doesn't pick whichever delegate happened to merge last.
+func electDefaultRoute(rObj types.Result, selector string, annots []utils.PluginInfo) (types.Result, error) {
+	result, err := current.NewResultFromResult(rObj)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't convert old result to current version: %v", err)
+	}
+
+	winnerIfName := selector
+	for i, pluginInfo := range annots {
+		if pluginInfo.PluginName != selector {
+			continue
+		}
+		if pluginInfo.IfName != "" {
+			winnerIfName = pluginInfo.IfName
+		} else {
+			winnerIfName = defaultIfName(i)
+		}
+		break
+	}
+
+	winnerIdx := -1
+	for i, iface := range result.Interfaces {
+		if iface.Name == winnerIfName {
+			winnerIdx = i
+			break
+		}
+	}
+	if winnerIdx == -1 {
+		return nil, fmt.Errorf("CNI Genie default-route annotation %q matched no delegate interface", selector)
+	}
+
+	var routes []*types.Route
+	for _, route := range result.Routes {
+		if !isDefaultRoute(route) {
+			routes = append(routes, route)
+			continue
+		}
+		if owner := routeOwnerInterface(result, route); owner == winnerIdx {
+			routes = append(routes, route)
+		}
+		// else: default route owned by a non-winning interface, drop it.
+	}
+	result.Routes = routes
+	return result, nil
+}
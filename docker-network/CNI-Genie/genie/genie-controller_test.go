@@ -0,0 +1,256 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genie
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Huawei-PaaS/CNI-Genie/utils"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/types/current"
+)
+
+func TestDefaultIfName(t *testing.T) {
+	cases := []struct {
+		i    int
+		want string
+	}{
+		{0, "eth0"},
+		{1, "net1"},
+		{2, "net2"},
+	}
+	for _, c := range cases {
+		if got := defaultIfName(c.i); got != c.want {
+			t.Errorf("defaultIfName(%d) = %q, want %q", c.i, got, c.want)
+		}
+	}
+}
+
+func TestValidateUniqueIfNames(t *testing.T) {
+	cases := []struct {
+		name    string
+		annots  []utils.PluginInfo
+		wantErr bool
+	}{
+		{
+			name: "distinct explicit names",
+			annots: []utils.PluginInfo{
+				{PluginName: "calico", IfName: "eth0"},
+				{PluginName: "weave", IfName: "net1"},
+			},
+		},
+		{
+			name: "defaulted names don't collide",
+			annots: []utils.PluginInfo{
+				{PluginName: "calico"},
+				{PluginName: "weave"},
+			},
+		},
+		{
+			name: "explicit name collides with another explicit name",
+			annots: []utils.PluginInfo{
+				{PluginName: "calico", IfName: "eth0"},
+				{PluginName: "weave", IfName: "eth0"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "explicit name collides with a defaulted name",
+			annots: []utils.PluginInfo{
+				{PluginName: "calico"},
+				{PluginName: "weave", IfName: "eth0"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateUniqueIfNames(c.annots)
+			if c.wantErr && err == nil {
+				t.Fatalf("expected an error, got nil")
+			}
+			if !c.wantErr && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseStructuredCNIAnnotation(t *testing.T) {
+	raw := `[
+		{"name":"calico","interface":"eth0","ips":["10.0.0.5"],"mac":"aa:bb:cc:dd:ee:ff"},
+		{"name":"weave","args":{"FOO":"bar"}}
+	]`
+	got, err := parseStructuredCNIAnnotation(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 delegates, got %d", len(got))
+	}
+	if got[0].PluginName != "calico" || got[0].IfName != "eth0" || got[0].Mac != "aa:bb:cc:dd:ee:ff" {
+		t.Errorf("unexpected first delegate: %+v", got[0])
+	}
+	if len(got[0].IPs) != 1 || got[0].IPs[0] != "10.0.0.5" {
+		t.Errorf("unexpected first delegate IPs: %+v", got[0].IPs)
+	}
+	if got[1].Args["FOO"] != "bar" {
+		t.Errorf("unexpected second delegate args: %+v", got[1].Args)
+	}
+}
+
+func TestParseStructuredCNIAnnotationInvalidJSON(t *testing.T) {
+	if _, err := parseStructuredCNIAnnotation("not json"); err == nil {
+		t.Fatalf("expected an error for malformed JSON")
+	}
+}
+
+func TestValidateRequestedAddressing(t *testing.T) {
+	ip, ipNet, _ := net.ParseCIDR("10.0.0.5/24")
+	ipNet.IP = ip
+	result := &current.Result{
+		Interfaces: []*current.Interface{{Name: "eth0", Mac: "aa:bb:cc:dd:ee:ff"}},
+		IPs: []*current.IPConfig{
+			{Version: "4", Interface: 0, Address: *ipNet},
+		},
+	}
+
+	t.Run("no request means no check", func(t *testing.T) {
+		if err := validateRequestedAddressing(utils.PluginInfo{PluginName: "calico"}, result); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("requested IP present, mask-optional", func(t *testing.T) {
+		requested := utils.PluginInfo{PluginName: "calico", IPs: []string{"10.0.0.5"}}
+		if err := validateRequestedAddressing(requested, result); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("requested IP present with a prefix length", func(t *testing.T) {
+		requested := utils.PluginInfo{PluginName: "calico", IPs: []string{"10.0.0.5/24"}}
+		if err := validateRequestedAddressing(requested, result); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("requested IP missing", func(t *testing.T) {
+		requested := utils.PluginInfo{PluginName: "calico", IPs: []string{"10.0.0.6"}}
+		if err := validateRequestedAddressing(requested, result); err == nil {
+			t.Fatalf("expected an error for an IP the delegate never assigned")
+		}
+	})
+
+	t.Run("requested MAC present", func(t *testing.T) {
+		requested := utils.PluginInfo{PluginName: "calico", Mac: "AA:BB:CC:DD:EE:FF"}
+		if err := validateRequestedAddressing(requested, result); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("requested MAC missing", func(t *testing.T) {
+		requested := utils.PluginInfo{PluginName: "calico", Mac: "11:22:33:44:55:66"}
+		if err := validateRequestedAddressing(requested, result); err == nil {
+			t.Fatalf("expected an error for a MAC the delegate never assigned")
+		}
+	})
+}
+
+func TestElectDefaultRoute(t *testing.T) {
+	_, defaultDst, _ := net.ParseCIDR("0.0.0.0/0")
+	gw0 := net.ParseIP("10.0.0.1")
+	gw1 := net.ParseIP("10.0.1.1")
+	ip0, ipNet0, _ := net.ParseCIDR("10.0.0.5/24")
+	ipNet0.IP = ip0
+	ip1, ipNet1, _ := net.ParseCIDR("10.0.1.5/24")
+	ipNet1.IP = ip1
+
+	result := &current.Result{
+		Interfaces: []*current.Interface{{Name: "eth0"}, {Name: "net1"}},
+		IPs: []*current.IPConfig{
+			{Version: "4", Interface: 0, Address: *ipNet0, Gateway: gw0},
+			{Version: "4", Interface: 1, Address: *ipNet1, Gateway: gw1},
+		},
+		Routes: []*types.Route{
+			{Dst: *defaultDst, GW: gw0},
+			{Dst: *defaultDst, GW: gw1},
+		},
+	}
+	annots := []utils.PluginInfo{
+		{PluginName: "calico", IfName: "eth0"},
+		{PluginName: "weave", IfName: "net1"},
+	}
+
+	got, err := electDefaultRoute(result, "weave", annots)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gotResult, err := current.NewResultFromResult(got)
+	if err != nil {
+		t.Fatalf("unexpected error converting result: %v", err)
+	}
+	if len(gotResult.Routes) != 1 {
+		t.Fatalf("expected exactly one surviving default route, got %d: %+v", len(gotResult.Routes), gotResult.Routes)
+	}
+	if !gotResult.Routes[0].GW.Equal(gw1) {
+		t.Errorf("expected the surviving default route to belong to the elected interface's gateway %v, got %v", gw1, gotResult.Routes[0].GW)
+	}
+}
+
+func TestElectDefaultRouteNoMatch(t *testing.T) {
+	result := &current.Result{
+		Interfaces: []*current.Interface{{Name: "eth0"}},
+	}
+	annots := []utils.PluginInfo{{PluginName: "calico", IfName: "eth0"}}
+
+	if _, err := electDefaultRoute(result, "unknown-plugin", annots); err == nil {
+		t.Fatalf("expected an error when the selector matches no delegate interface")
+	}
+}
+
+func TestMergeMultiIPPreferences(t *testing.T) {
+	ip0, ipNet0, _ := net.ParseCIDR("10.0.0.5/24")
+	ipNet0.IP = ip0
+	ip1, ipNet1, _ := net.ParseCIDR("10.0.1.5/24")
+	ipNet1.IP = ip1
+
+	first := &current.Result{
+		Interfaces: []*current.Interface{{Name: "eth0"}},
+		IPs:        []*current.IPConfig{{Version: "4", Interface: 0, Address: *ipNet0}},
+	}
+	second := &current.Result{
+		Interfaces: []*current.Interface{{Name: "net1"}},
+		IPs:        []*current.IPConfig{{Version: "4", Interface: 0, Address: *ipNet1}},
+	}
+
+	prefs := mergeMultiIPPreferences(utils.MultiIPPreferences{}, "eth0", first)
+	prefs = mergeMultiIPPreferences(prefs, "net1", second)
+
+	if prefs.MultiEntry != 2 {
+		t.Fatalf("expected MultiEntry to be 2 after merging two delegates' results, got %d", prefs.MultiEntry)
+	}
+	if len(prefs.Ips) != 2 {
+		t.Fatalf("expected 2 distinct keys in Ips, got %d: %+v", len(prefs.Ips), prefs.Ips)
+	}
+	if prefs.Ips["ip1"].IP != "10.0.0.5" {
+		t.Errorf("expected ip1 to be the first delegate's address, got %+v", prefs.Ips["ip1"])
+	}
+	if prefs.Ips["ip2"].IP != "10.0.1.5" {
+		t.Errorf("expected ip2 to be the second delegate's address, got %+v", prefs.Ips["ip2"])
+	}
+	if len(prefs.IfaceIPs["eth0"]) != 1 || len(prefs.IfaceIPs["net1"]) != 1 {
+		t.Errorf("expected one IP grouped under each interface, got %+v", prefs.IfaceIPs)
+	}
+}
@@ -0,0 +1,161 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genie
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Huawei-PaaS/CNI-Genie/utils"
+	"github.com/containernetworking/cni/libcni"
+	nadclient "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// GetNADClient builds a typed client for the Network Plumbing WG
+// NetworkAttachmentDefinition CRD from the same kubeconfig file Genie
+// already uses for its regular Kubernetes clientset (see GetKubeClient).
+// The "policy" and "k8s_api_root" overrides GetKubeClient applies are not
+// applied here.
+func GetNADClient(conf utils.NetConf) (nadclient.Interface, error) {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: conf.Kubernetes.Kubeconfig},
+		&clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return nadclient.NewForConfig(config)
+}
+
+// nadSelector is one entry of the Network Plumbing WG "networks" annotation,
+// either parsed out of the short "namespace/name@ifname" form or directly
+// unmarshalled from the JSON list form.
+// See https://github.com/k8snetworkplumbingwg/multi-net-spec.
+type nadSelector struct {
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace,omitempty"`
+	Interface string   `json:"interface,omitempty"`
+	IPs       []string `json:"ips,omitempty"`
+	Mac       string   `json:"mac,omitempty"`
+}
+
+// isNADAnnotation reports whether networksAnnot looks like the Network
+// Plumbing WG "networks" annotation (a JSON list, or "ns/name@if" entries)
+// rather than Genie's own internal CRD format.
+func isNADAnnotation(networksAnnot string) bool {
+	trimmed := strings.TrimSpace(networksAnnot)
+	if strings.HasPrefix(trimmed, "[") {
+		return true
+	}
+	return strings.Contains(trimmed, "/") || strings.Contains(trimmed, "@")
+}
+
+// parseNADSelectors parses the "networks" annotation value into a list of
+// nadSelector, accepting both the comma-separated short form
+// ("ns/foo@net1,ns/bar") and the JSON list form.
+func parseNADSelectors(networksAnnot string, defaultNamespace string) ([]nadSelector, error) {
+	trimmed := strings.TrimSpace(networksAnnot)
+	if strings.HasPrefix(trimmed, "[") {
+		var selectors []nadSelector
+		if err := json.Unmarshal([]byte(trimmed), &selectors); err != nil {
+			return nil, fmt.Errorf("CNI Genie failed to parse NAD networks annotation as JSON: %v", err)
+		}
+		for i := range selectors {
+			if selectors[i].Namespace == "" {
+				selectors[i].Namespace = defaultNamespace
+			}
+		}
+		return selectors, nil
+	}
+
+	var selectors []nadSelector
+	for _, entry := range strings.Split(trimmed, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		sel := nadSelector{Namespace: defaultNamespace}
+
+		nameAndIface := entry
+		if idx := strings.Index(entry, "@"); idx != -1 {
+			nameAndIface = entry[:idx]
+			sel.Interface = entry[idx+1:]
+		}
+		if idx := strings.Index(nameAndIface, "/"); idx != -1 {
+			sel.Namespace = nameAndIface[:idx]
+			sel.Name = nameAndIface[idx+1:]
+		} else {
+			sel.Name = nameAndIface
+		}
+		selectors = append(selectors, sel)
+	}
+	return selectors, nil
+}
+
+// GetPluginInfoFromNAD resolves the Network Plumbing WG "networks"
+// annotation against real NetworkAttachmentDefinition (k8s.cni.cncf.io/v1)
+// objects, so pods already annotated for Multus can be scheduled onto
+// Genie without rewriting their spec. Each selector's NAD is fetched via
+// the typed NAD clientset; if the NAD embeds a "config" field it is
+// carried through as PluginInfo.RawConfig so resolveDelegateConf uses it
+// directly as the delegate's NetworkConfigList, otherwise the NAD's name
+// is resolved to a conf file under DefaultNetDir exactly as Genie's
+// internal plugin names are.
+func GetPluginInfoFromNAD(networksAnnot string, defaultNamespace string, nadClient nadclient.Interface) ([]utils.PluginInfo, error) {
+	selectors, err := parseNADSelectors(networksAnnot, defaultNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var pluginInfos []utils.PluginInfo
+	for i, sel := range selectors {
+		nad, err := nadClient.K8sCniCncfIoV1().NetworkAttachmentDefinitions(sel.Namespace).Get(sel.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("CNI Genie failed to fetch NetworkAttachmentDefinition %s/%s: %v", sel.Namespace, sel.Name, err)
+		}
+
+		ifName := sel.Interface
+		if ifName == "" {
+			ifName = fmt.Sprintf("net%d", i+1)
+		}
+
+		pluginInfo := utils.PluginInfo{
+			IfName: ifName,
+			IPs:    sel.IPs,
+			Mac:    sel.Mac,
+		}
+		if strings.TrimSpace(nad.Spec.Config) != "" {
+			// The NAD embeds its own config; carry the raw bytes through so
+			// resolveDelegateConf runs it straight through libcni instead
+			// of resolving it to a plugin name/conf file (the embedded
+			// config may not exist on disk at all, and may differ from
+			// whatever does).
+			confList, err := libcni.ConfListFromBytes([]byte(nad.Spec.Config))
+			if err != nil {
+				return nil, fmt.Errorf("CNI Genie failed to parse embedded config for NAD %s/%s: %v", sel.Namespace, sel.Name, err)
+			}
+			pluginInfo.PluginName = confList.Plugins[0].Network.Type
+			pluginInfo.RawConfig = []byte(nad.Spec.Config)
+		} else {
+			// No embedded config: fall back to resolving the NAD name as a
+			// plugin type, the same way Genie resolves "cni" annotation
+			// entries against DefaultNetDir.
+			pluginInfo.PluginName = sel.Name
+		}
+
+		pluginInfos = append(pluginInfos, pluginInfo)
+	}
+	return pluginInfos, nil
+}
@@ -0,0 +1,71 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genie
+
+import "testing"
+
+func TestParseNADSelectorsShortForm(t *testing.T) {
+	selectors, err := parseNADSelectors("other-ns/foo@net1,bar", "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(selectors) != 2 {
+		t.Fatalf("expected 2 selectors, got %d: %+v", len(selectors), selectors)
+	}
+	if selectors[0].Namespace != "other-ns" || selectors[0].Name != "foo" || selectors[0].Interface != "net1" {
+		t.Errorf("unexpected first selector: %+v", selectors[0])
+	}
+	if selectors[1].Namespace != "default" || selectors[1].Name != "bar" || selectors[1].Interface != "" {
+		t.Errorf("unexpected second selector, expected default namespace to be filled in: %+v", selectors[1])
+	}
+}
+
+func TestParseNADSelectorsJSONForm(t *testing.T) {
+	raw := `[{"name":"foo","namespace":"other-ns","interface":"net1","ips":["10.0.0.5"],"mac":"aa:bb:cc:dd:ee:ff"},{"name":"bar"}]`
+	selectors, err := parseNADSelectors(raw, "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(selectors) != 2 {
+		t.Fatalf("expected 2 selectors, got %d: %+v", len(selectors), selectors)
+	}
+	if selectors[0].Namespace != "other-ns" || selectors[0].Mac != "aa:bb:cc:dd:ee:ff" || len(selectors[0].IPs) != 1 {
+		t.Errorf("unexpected first selector: %+v", selectors[0])
+	}
+	if selectors[1].Namespace != "default" {
+		t.Errorf("expected default namespace to be filled in for entries that omit it, got %+v", selectors[1])
+	}
+}
+
+func TestParseNADSelectorsInvalidJSON(t *testing.T) {
+	if _, err := parseNADSelectors("[not valid json", "default"); err == nil {
+		t.Fatalf("expected an error for malformed JSON input")
+	}
+}
+
+func TestIsNADAnnotation(t *testing.T) {
+	cases := []struct {
+		annot string
+		want  bool
+	}{
+		{`[{"name":"foo"}]`, true},
+		{"default/foo", true},
+		{"foo@net1", true},
+		{"canal,weave", false},
+	}
+	for _, c := range cases {
+		if got := isNADAnnotation(c.annot); got != c.want {
+			t.Errorf("isNADAnnotation(%q) = %v, want %v", c.annot, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genie
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Huawei-PaaS/CNI-Genie/utils"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/types/current"
+)
+
+// ResultCacheDir holds one JSON file per (container, network, ifname), the
+// delegated plugin's own types.Result. AttachmentRecord (state.go) already
+// lets DEL/CHECK rebuild the RuntimeConf used for a delegate; this cache
+// additionally keeps the *result* that delegate returned, so a partially
+// failed ADD can roll back the delegates that already succeeded without
+// needing to re-derive anything from pod annotations.
+const ResultCacheDir = "/var/lib/cni/genie/results"
+
+func resultCacheContainerDir(containerID string) string {
+	return filepath.Join(ResultCacheDir, containerID)
+}
+
+func resultCachePath(containerID, network, ifName string) string {
+	return filepath.Join(resultCacheContainerDir(containerID), fmt.Sprintf("%s-%s.json", network, ifName))
+}
+
+// persistResult caches a single delegate's result, creating the
+// container's cache directory if necessary.
+func persistResult(containerID, network, ifName string, result types.Result) error {
+	currResult, err := current.NewResultFromResult(result)
+	if err != nil {
+		return fmt.Errorf("CNI Genie failed to convert result to current version for %s/%s: %v", network, ifName, err)
+	}
+	if err := os.MkdirAll(resultCacheContainerDir(containerID), 0755); err != nil {
+		return fmt.Errorf("CNI Genie failed to create result cache dir for %s: %v", containerID, err)
+	}
+	data, err := json.MarshalIndent(currResult, "", "  ")
+	if err != nil {
+		return fmt.Errorf("CNI Genie failed to marshal cached result for %s/%s: %v", network, ifName, err)
+	}
+	return ioutil.WriteFile(resultCachePath(containerID, network, ifName), data, ConfFilePermission)
+}
+
+// loadResult reads back a result cached by persistResult, or (nil, nil) if
+// none exists.
+func loadResult(containerID, network, ifName string) (*current.Result, error) {
+	data, err := ioutil.ReadFile(resultCachePath(containerID, network, ifName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CNI Genie failed to read cached result for %s/%s: %v", network, ifName, err)
+	}
+	result := &current.Result{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("CNI Genie failed to parse cached result for %s/%s: %v", network, ifName, err)
+	}
+	return result, nil
+}
+
+// removeResult deletes a single cached result. It is not an error for the
+// entry to already be gone.
+func removeResult(containerID, network, ifName string) error {
+	err := os.Remove(resultCachePath(containerID, network, ifName))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("CNI Genie failed to remove cached result for %s/%s: %v", network, ifName, err)
+	}
+	return nil
+}
+
+// removeResultsForContainer prunes every cached result for containerID, as
+// DeletePodNetworkWithClient does once it has successfully torn down every
+// delegate.
+func removeResultsForContainer(containerID string) error {
+	err := os.RemoveAll(resultCacheContainerDir(containerID))
+	if err != nil {
+		return fmt.Errorf("CNI Genie failed to prune result cache for %s: %v", containerID, err)
+	}
+	return nil
+}
+
+// ListResultCacheContainerIDs returns every containerID that still has at
+// least one cached delegate result on disk. It is used by the GC
+// reconciler to find leaked IPAM allocations whose attachment record (if
+// any) has already gone missing, e.g. if the process was killed between
+// persisting the cache and persisting the attachment record.
+func ListResultCacheContainerIDs() ([]string, error) {
+	entries, err := ioutil.ReadDir(ResultCacheDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CNI Genie failed to list %s: %v", ResultCacheDir, err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ids = append(ids, entry.Name())
+		}
+	}
+	return ids, nil
+}
+
+// cachedEntry identifies one (network, ifname) pair cached for a
+// container, recovered from its cache filename.
+type cachedEntry struct {
+	network string
+	ifName  string
+}
+
+func listCachedEntries(containerID string) ([]cachedEntry, error) {
+	files, err := ioutil.ReadDir(resultCacheContainerDir(containerID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CNI Genie failed to list cached results for %s: %v", containerID, err)
+	}
+
+	var entries []cachedEntry
+	for _, f := range files {
+		name := strings.TrimSuffix(f.Name(), ".json")
+		network, ifName := name, ""
+		if idx := strings.LastIndex(name, "-"); idx >= 0 {
+			network, ifName = name[:idx], name[idx+1:]
+		}
+		entries = append(entries, cachedEntry{network: network, ifName: ifName})
+	}
+	return entries, nil
+}
+
+// ReclaimCachedResults tears down every delegate this container still has
+// a cached result for and prunes the cache, without needing an
+// AttachmentRecord: the (network, ifname) pair recovered from each cache
+// filename is enough to rebuild a minimal RuntimeConf via deleteNetwork.
+// This is the GC reconciler's backstop for the case where a process died
+// after caching a delegate's result but before persisting the full
+// attachment record.
+func ReclaimCachedResults(containerID string) error {
+	entries, err := listCachedEntries(containerID)
+	if err != nil {
+		return err
+	}
+
+	cniArgs := utils.CNIArgs{ContainerID: containerID}
+	var newErr error
+	for _, entry := range entries {
+		pluginInfo := utils.PluginInfo{PluginName: entry.network, IfName: entry.ifName}
+		if err := deleteNetwork(entry.ifName, pluginInfo, cniArgs); err != nil {
+			fmt.Fprintf(os.Stderr, "CNI Genie GC failed to delete cached network %s/%s for %s: %v\n", entry.network, entry.ifName, containerID, err)
+			newErr = err
+		}
+	}
+	if err := removeResultsForContainer(containerID); err != nil {
+		return err
+	}
+	if newErr != nil {
+		return fmt.Errorf("CNI Genie GC failed to fully reclaim cached results for %s: %v", containerID, newErr)
+	}
+	return nil
+}
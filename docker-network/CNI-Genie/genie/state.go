@@ -0,0 +1,161 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genie
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Huawei-PaaS/CNI-Genie/utils"
+)
+
+// AttachmentDir holds one JSON record per attached container, so DEL can
+// reconstruct exactly what ADD did without re-querying the apiserver. A
+// var rather than a const so tests can point it at a scratch directory
+// instead of the real node-wide path.
+var AttachmentDir = "/var/lib/cni-genie"
+
+// AttachmentRecord is the exact set of delegates (and the netns they were
+// attached in) Genie used for a container's ADD. It is written right after
+// a successful AddPodNetworkWithClient and consulted first on DEL and CHECK,
+// making both idempotent even if the pod's annotations have since changed
+// or the apiserver is unreachable. Each entry's IfName is unique within a
+// record (AddPodNetworkWithClient rejects two delegates requesting the same
+// one), so a single record unambiguously identifies every attached network
+// by PluginName+IfName without needing a separate index.
+type AttachmentRecord struct {
+	ContainerID string             `json:"containerID"`
+	Netns       string             `json:"netns"`
+	Plugins     []utils.PluginInfo `json:"plugins"`
+}
+
+func attachmentPath(containerID string) string {
+	return filepath.Join(AttachmentDir, containerID+".json")
+}
+
+// persistAttachment writes the attachment record for containerID, creating
+// AttachmentDir if necessary.
+func persistAttachment(record AttachmentRecord) error {
+	if err := os.MkdirAll(AttachmentDir, 0755); err != nil {
+		return fmt.Errorf("CNI Genie failed to create %s: %v", AttachmentDir, err)
+	}
+	data, err := json.MarshalIndent(&record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("CNI Genie failed to marshal attachment record for %s: %v", record.ContainerID, err)
+	}
+	if err := ioutil.WriteFile(attachmentPath(record.ContainerID), data, ConfFilePermission); err != nil {
+		return fmt.Errorf("CNI Genie failed to write attachment record for %s: %v", record.ContainerID, err)
+	}
+	return nil
+}
+
+// loadAttachment reads back the attachment record written by
+// persistAttachment, or (nil, nil) if none exists - e.g. the very first
+// time a container not created through this binary is torn down.
+func loadAttachment(containerID string) (*AttachmentRecord, error) {
+	data, err := ioutil.ReadFile(attachmentPath(containerID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CNI Genie failed to read attachment record for %s: %v", containerID, err)
+	}
+	var record AttachmentRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("CNI Genie failed to parse attachment record for %s: %v", containerID, err)
+	}
+	return &record, nil
+}
+
+// removeAttachment deletes the attachment record for containerID. It is
+// not an error for the record to already be gone - DEL is expected to be
+// idempotent.
+func removeAttachment(containerID string) error {
+	err := os.Remove(attachmentPath(containerID))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("CNI Genie failed to remove attachment record for %s: %v", containerID, err)
+	}
+	return nil
+}
+
+// ListAttachments returns every attachment record currently on disk. It is
+// used by the GC reconciler to find containers Genie still believes are
+// attached.
+func ListAttachments() ([]AttachmentRecord, error) {
+	entries, err := ioutil.ReadDir(AttachmentDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CNI Genie failed to list %s: %v", AttachmentDir, err)
+	}
+
+	var records []AttachmentRecord
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		containerID := entry.Name()[:len(entry.Name())-len(".json")]
+		record, err := loadAttachment(containerID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "CNI Genie GC skipping unreadable attachment record %s: %v\n", entry.Name(), err)
+			continue
+		}
+		if record != nil {
+			records = append(records, *record)
+		}
+	}
+	return records, nil
+}
+
+// deleteRecordedPlugins tears down every delegate in record using cniArgs
+// verbatim - it's the caller's job to decide what that cniArgs should be,
+// since a live DEL and an orphan reconciliation have very different ideas
+// of what's available (see ReconcileOrphan vs. DeletePodNetworkWithClient).
+// It attempts every delegate even after an error and returns the last one.
+func deleteRecordedPlugins(record AttachmentRecord, cniArgs utils.CNIArgs) error {
+	var newErr error
+	for _, pluginInfo := range record.Plugins {
+		if err := deleteNetwork(pluginInfo.IfName, pluginInfo, cniArgs); err != nil {
+			fmt.Fprintf(os.Stderr, "CNI Genie failed to delete network %s for %s: %v\n", pluginInfo.PluginName, record.ContainerID, err)
+			newErr = err
+		}
+	}
+	return newErr
+}
+
+// ReconcileOrphan tears down every delegate recorded for an attachment that
+// the caller has determined no longer has a running container (e.g. absent
+// from the CRI socket's container list), then removes its attachment
+// record. It does not need apiserver access since it replays exactly the
+// delegates/ifnames used at ADD time. Unlike a live DEL, there is no
+// skel.CmdArgs to carry forward here - the container that would have
+// supplied Args/Path/StdinData is gone - so delegates are called with just
+// the ContainerID/Netns the record itself retained.
+func ReconcileOrphan(record AttachmentRecord) error {
+	cniArgs := utils.CNIArgs{
+		ContainerID: record.ContainerID,
+		Netns:       record.Netns,
+	}
+
+	if err := deleteRecordedPlugins(record, cniArgs); err != nil {
+		return fmt.Errorf("CNI Genie GC failed to fully reconcile orphan %s: %v", record.ContainerID, err)
+	}
+	if err := removeResultsForContainer(record.ContainerID); err != nil {
+		fmt.Fprintf(os.Stderr, "CNI Genie GC failed to prune cached results for %s: %v\n", record.ContainerID, err)
+	}
+	return removeAttachment(record.ContainerID)
+}
@@ -0,0 +1,185 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cniserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Huawei-PaaS/CNI-Genie/genie"
+	"github.com/Huawei-PaaS/CNI-Genie/utils"
+	"github.com/containernetworking/cni/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// kubeClientBackoff bounds how long NewServer retries a failed kube API
+// lookup before giving up - the apiserver, or the node's network, may not
+// be up yet when the DaemonSet's cniserver container starts.
+var kubeClientBackoff = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   2,
+	Steps:    6,
+}
+
+// Server is the cniserver daemon. It holds one persistent kube clientset for
+// the lifetime of the process and serializes delegate calls per pod so two
+// concurrent CNI invocations for the same pod (e.g. ADD racing a late DEL)
+// can't interleave their libcni calls.
+type Server struct {
+	kubeClient *kubernetes.Clientset
+	conf       utils.NetConf
+
+	podLocksMu sync.Mutex
+	podLocks   map[string]*sync.Mutex
+}
+
+// NewServer builds a Server from the given netconf and dials the kube
+// clientset once, up front, instead of per CNI invocation. Building the
+// clientset itself rarely fails, but the apiserver may not be reachable yet
+// when the cniserver DaemonSet starts, so the first live API call
+// (fetching "default") is retried with backoff rather than failing fast.
+func NewServer(conf utils.NetConf) (*Server, error) {
+	kubeClient, err := genie.GetKubeClient(conf)
+	if err != nil {
+		return nil, fmt.Errorf("cniserver: failed to build kube client: %v", err)
+	}
+
+	var lastErr error
+	err = wait.ExponentialBackoff(kubeClientBackoff, func() (bool, error) {
+		_, lastErr = kubeClient.CoreV1().Namespaces().Get("default", metav1.GetOptions{})
+		return lastErr == nil, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cniserver: apiserver unreachable after retrying: %v", lastErr)
+	}
+
+	return &Server{
+		kubeClient: kubeClient,
+		conf:       conf,
+		podLocks:   map[string]*sync.Mutex{},
+	}, nil
+}
+
+// ListenAndServe listens on the given unix socket path and serves requests
+// until the process is killed. The socket file is removed and recreated on
+// startup so a crashed server doesn't leave a stale socket behind.
+func (s *Server) ListenAndServe(socketPath string) error {
+	if err := os.RemoveAll(socketPath); err != nil {
+		return fmt.Errorf("cniserver: failed to clear stale socket %s: %v", socketPath, err)
+	}
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("cniserver: failed to listen on %s: %v", socketPath, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cmd", s.handleCmd)
+	mux.HandleFunc("/readyz", s.handleReady)
+	return http.Serve(listener, mux)
+}
+
+// handleReady answers readiness probes: the server is ready once its kube
+// clientset can reach the apiserver, which NewServer already waited for, so
+// this just re-checks liveness of that same connection on every call.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.kubeClient.CoreV1().Namespaces().Get("default", metav1.GetOptions{}); err != nil {
+		http.Error(w, fmt.Sprintf("apiserver unreachable: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// podLock returns the mutex guarding delegate calls for containerID,
+// creating it on first use. Locks are intentionally never removed: the
+// server process is short-lived relative to node uptime and the per-pod
+// lock footprint is small compared to the cost of a missed serialization.
+func (s *Server) podLock(containerID string) *sync.Mutex {
+	s.podLocksMu.Lock()
+	defer s.podLocksMu.Unlock()
+	l, ok := s.podLocks[containerID]
+	if !ok {
+		l = &sync.Mutex{}
+		s.podLocks[containerID] = l
+	}
+	return l
+}
+
+func (s *Server) handleCmd(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, fmt.Errorf("cniserver: failed to decode request: %v", err))
+		return
+	}
+
+	lock := s.podLock(req.ContainerID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	cniArgs := utils.CNIArgs{
+		Args:        req.Args,
+		StdinData:   req.StdinData,
+		Path:        req.Path,
+		Netns:       req.Netns,
+		ContainerID: req.ContainerID,
+		IfName:      req.IfName,
+	}
+
+	var result types.Result
+	var err error
+	switch req.Cmd {
+	case CmdAdd:
+		result, err = genie.AddPodNetworkWithClient(s.kubeClient, cniArgs, s.conf)
+	case CmdDel:
+		err = genie.DeletePodNetworkWithClient(s.kubeClient, cniArgs, s.conf)
+	case CmdCheck:
+		err = genie.CheckPodNetwork(cniArgs)
+	default:
+		err = fmt.Errorf("cniserver: unsupported cmd %q", req.Cmd)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := Response{}
+	if result != nil {
+		resultBytes, merr := json.Marshal(result)
+		if merr != nil {
+			writeError(w, fmt.Errorf("cniserver: failed to marshal result: %v", merr))
+			return
+		}
+		resp.Result = resultBytes
+	}
+	writeResponse(w, resp)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeResponse(w, Response{Error: err.Error()})
+}
+
+func writeResponse(w http.ResponseWriter, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Error != "" {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		fmt.Fprintf(os.Stderr, "cniserver: failed to write response: %v\n", err)
+	}
+}
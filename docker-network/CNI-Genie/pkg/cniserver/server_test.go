@@ -0,0 +1,179 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cniserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Huawei-PaaS/CNI-Genie/genie"
+	"github.com/Huawei-PaaS/CNI-Genie/utils"
+)
+
+// newTestServer builds a Server bypassing NewServer (which dials a real
+// apiserver), so the socket/routing plumbing can be exercised without a
+// Kubernetes cluster.
+func newTestServer() *Server {
+	return &Server{podLocks: map[string]*sync.Mutex{}}
+}
+
+func TestHandleCmdRejectsUnsupportedCommand(t *testing.T) {
+	srv := newTestServer()
+	body, err := json.Marshal(&Request{Cmd: "BOGUS", ContainerID: "c1"})
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/cmd", bytes.NewReader(body))
+	srv.handleCmd(recorder, req)
+
+	var resp Response
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Error == "" {
+		t.Fatalf("expected an error for an unsupported cmd, got %+v", resp)
+	}
+}
+
+func TestListenAndServeOverUnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "cni.sock")
+	srv := newTestServer()
+
+	go srv.ListenAndServe(socketPath)
+	waitForSocket(t, socketPath)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+				return net.Dial("unix", socketPath)
+			},
+		},
+	}
+
+	body, err := json.Marshal(&Request{Cmd: CmdCheck, ContainerID: "c1", IfName: "eth0"})
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+	resp, err := client.Post("http://cni-genie/cmd", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to POST over unix socket: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var parsed Response
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	// With no attachment record on disk for "c1", CHECK is expected to
+	// surface an error rather than silently succeed.
+	if parsed.Error == "" {
+		t.Fatalf("expected CHECK to fail for an unknown container, got %+v", parsed)
+	}
+}
+
+func TestHandleCmdAddPropagatesLoadArgsError(t *testing.T) {
+	srv := newTestServer()
+	// A malformed CNI_ARGS string fails in genie.AddPodNetworkWithClient's
+	// very first step (loadArgs), before the kube clientset is ever
+	// touched, so this exercises handleCmd's ADD wiring without needing a
+	// real apiserver.
+	body, err := json.Marshal(&Request{Cmd: CmdAdd, ContainerID: "c-add", Args: "not-a-valid-arg"})
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/cmd", bytes.NewReader(body))
+	srv.handleCmd(recorder, req)
+
+	var resp Response
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Error == "" {
+		t.Fatalf("expected ADD to surface the loadArgs error, got %+v", resp)
+	}
+}
+
+func TestHandleCmdDelReplaysAttachmentRecord(t *testing.T) {
+	origAttachmentDir := genie.AttachmentDir
+	genie.AttachmentDir = t.TempDir()
+	defer func() { genie.AttachmentDir = origAttachmentDir }()
+
+	containerID := "c-del"
+	record := genie.AttachmentRecord{
+		ContainerID: containerID,
+		Netns:       "/var/run/netns/c-del",
+		Plugins: []utils.PluginInfo{
+			{PluginName: "nonexistent-test-plugin", IfName: "eth0"},
+		},
+	}
+	data, err := json.Marshal(&record)
+	if err != nil {
+		t.Fatalf("failed to marshal attachment record: %v", err)
+	}
+	recordPath := filepath.Join(genie.AttachmentDir, containerID+".json")
+	if err := ioutil.WriteFile(recordPath, data, 0644); err != nil {
+		t.Fatalf("failed to write attachment record: %v", err)
+	}
+
+	srv := newTestServer()
+	body, err := json.Marshal(&Request{Cmd: CmdDel, ContainerID: containerID, Netns: record.Netns})
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/cmd", bytes.NewReader(body))
+	srv.handleCmd(recorder, req)
+
+	var resp Response
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	// DEL replays the recorded delegate, which has no conf file under
+	// DefaultNetDir and no binary under DefaultPluginDir in this test
+	// environment, so it's expected to fail rather than silently no-op -
+	// the point of this test is that it went through the record-driven
+	// path (no apiserver access) rather than the unsupported-cmd default.
+	if resp.Error == "" {
+		t.Fatalf("expected DEL to surface an error resolving the recorded delegate, got %+v", resp)
+	}
+}
+
+// waitForSocket polls until socketPath exists or the test deadline passes;
+// ListenAndServe runs in a goroutine so the socket isn't guaranteed to exist
+// the instant it's spawned.
+func waitForSocket(t *testing.T, socketPath string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(socketPath); err == nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("socket %s was never created", socketPath)
+}
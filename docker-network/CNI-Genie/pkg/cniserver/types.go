@@ -0,0 +1,54 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package cniserver implements the long-running counterpart of the cnishim
+binary. It owns the Kubernetes clientset, resolves pod annotations and
+delegates to the CNI plugins chosen by genie.AddPodNetwork/DeletePodNetwork,
+so that the per-invocation cnishim stays a thin CNI binary with no
+kubeconfig of its own.
+*/
+package cniserver
+
+// CmdType identifies which CNI verb a Request is carrying.
+type CmdType string
+
+const (
+	CmdAdd   CmdType = "ADD"
+	CmdDel   CmdType = "DEL"
+	CmdCheck CmdType = "CHECK"
+)
+
+// DefaultSocketPath is where the cniserver listens and cnishim dials. The
+// shim always dials this path; it is not read from the network config.
+const DefaultSocketPath = "/var/run/cni-genie/cni.sock"
+
+// Request is what cnishim POSTs to the cniserver for every CNI invocation.
+// It carries everything skel.CmdArgs holds plus the raw netconf bytes, since
+// the server - not the shim - owns parsing of utils.NetConf.
+type Request struct {
+	Cmd         CmdType `json:"cmd"`
+	ContainerID string  `json:"containerID"`
+	Netns       string  `json:"netns"`
+	IfName      string  `json:"ifName"`
+	Args        string  `json:"args"`
+	Path        string  `json:"path"`
+	StdinData   []byte  `json:"stdinData"`
+}
+
+// Response is what the cniserver sends back. At most one of Result/Error is
+// set: Error on any failure, Result on a successful ADD, and neither on a
+// successful DEL or CHECK.
+type Response struct {
+	Result []byte `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
@@ -0,0 +1,143 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package utils holds the data types shared between the genie package,
+// the plugins package and the CNI conf files on disk: the CNI args Genie
+// is invoked with, the genie netconf schema, and the annotation schemas
+// Genie reads from and writes back to pod definitions.
+package utils
+
+import (
+	"github.com/containernetworking/cni/pkg/types"
+)
+
+// CNIArgs is Genie's native wrapper around the fields of skel.CmdArgs that
+// it actually needs to carry through the add/delete pipeline.
+type CNIArgs struct {
+	Args        string
+	StdinData   []byte
+	Path        string
+	Netns       string
+	ContainerID string
+	IfName      string
+}
+
+// K8sArgs is the set of CNI_ARGS kubelet passes to every CNI plugin,
+// unmarshalled via types.LoadArgs.
+type K8sArgs struct {
+	types.CommonArgs
+	K8S_POD_NAME               types.UnmarshallableString
+	K8S_POD_NAMESPACE          types.UnmarshallableString
+	K8S_POD_INFRA_CONTAINER_ID types.UnmarshallableString
+	// K8S_ANNOT carries a JSON-encoded annotation map, used as a fallback
+	// when the pod definition itself can't be fetched from the apiserver.
+	K8S_ANNOT types.UnmarshallableString
+}
+
+// NetConf is Genie's own netconf schema, the top-level JSON object found in
+// /etc/cni/net.d/*genie*.conf.
+type NetConf struct {
+	types.NetConf
+	DefaultPlugin string `json:"default_plugin"`
+	Policy        struct {
+		K8sAPIRoot              string `json:"k8s_api_root"`
+		K8sClientCertificate    string `json:"k8s_client_certificate"`
+		K8sClientKey            string `json:"k8s_client_key"`
+		K8sCertificateAuthority string `json:"k8s_certificate_authority"`
+		K8sAuthToken            string `json:"k8s_auth_token"`
+	} `json:"policy"`
+	Kubernetes struct {
+		Kubeconfig string `json:"kubeconfig"`
+		K8sAPIRoot string `json:"k8s_api_root"`
+	} `json:"kubernetes"`
+}
+
+// PluginInfo describes a single delegate network requested for a pod,
+// either via the legacy "cni" annotation or the "networks" annotation.
+type PluginInfo struct {
+	PluginName string
+	IfName     string
+	Subnet     string
+	// IPs, Mac and DefaultRoute let a caller pin a delegate's static
+	// addressing, parsed either from the "cni" annotation's extended
+	// "plugin;ip=..;mac=.." syntax or from a NAD-style "networks" entry.
+	IPs          []string
+	Mac          string
+	DefaultRoute bool
+	// PortMappings are only set for the delegate designated as the pod's
+	// primary interface (see the "cni.genie/primary-interface" annotation)
+	// and are passed through as the CNI portMappings runtime capability.
+	PortMappings []PortMapping
+	// Args carries arbitrary extra CNI_ARGS key/value pairs for this
+	// delegate, as parsed from the structured "cni" annotation's "args"
+	// object. This is how SR-IOV/DPDK-style delegates that need their own
+	// CNI_ARGS (e.g. a VF pool name) get them without Genie special-casing
+	// every possible key.
+	Args map[string]string
+	// RawConfig is a delegate's full CNI config/conflist JSON, set when it
+	// was resolved from a NetworkAttachmentDefinition that embeds its own
+	// "config" field (see nad.go's GetPluginInfoFromNAD). When set,
+	// resolveDelegateConf uses it directly instead of name-matching a conf
+	// file under DefaultNetDir, since the embedded config is authoritative
+	// and may not exist on disk at all.
+	RawConfig []byte
+}
+
+// PortMapping mirrors the CNI portMappings runtime capability argument,
+// see github.com/containernetworking/plugins/plugins/meta/portmap.
+type PortMapping struct {
+	HostPort      int    `json:"hostPort"`
+	ContainerPort int    `json:"containerPort"`
+	Protocol      string `json:"protocol,omitempty"`
+	HostIP        string `json:"hostIP,omitempty"`
+}
+
+// IPAddressPreferences records one IP assigned to a pod interface by a
+// delegated CNI plugin, together with enough of the CNI 0.3.1 result shape
+// (version, gateway) for downstream consumers to reconstruct routing
+// without re-invoking the delegate.
+type IPAddressPreferences struct {
+	IP        string `json:"ip"`
+	Version   string `json:"version,omitempty"`
+	Gateway   string `json:"gateway,omitempty"`
+	Interface string `json:"interface"`
+}
+
+// MultiIPPreferences is the schema written to the "multi-ip-preferences"
+// pod annotation. Ips is keyed "ip1", "ip2", ... in allocation order;
+// IfaceIPs groups the same addresses by interface name so a consumer can
+// answer "what are all the IPs on eth1" without re-deriving it from Ips.
+// Routes/DNS mirror the merged CNI result so the full per-interface
+// picture survives round-tripping through the annotation.
+type MultiIPPreferences struct {
+	MultiEntry int                               `json:"multi_entry"`
+	Ips        map[string]IPAddressPreferences   `json:"ips"`
+	IfaceIPs   map[string][]IPAddressPreferences `json:"iface_ips,omitempty"`
+	Routes     []RoutePreference                 `json:"routes,omitempty"`
+	DNS        DNSPreference                     `json:"dns,omitempty"`
+}
+
+// RoutePreference is a trimmed-down, JSON-friendly copy of
+// github.com/containernetworking/cni/pkg/types/current.Route.
+type RoutePreference struct {
+	Dst string `json:"dst"`
+	GW  string `json:"gw,omitempty"`
+}
+
+// DNSPreference is a trimmed-down, JSON-friendly copy of
+// github.com/containernetworking/cni/pkg/types.DNS.
+type DNSPreference struct {
+	Nameservers []string `json:"nameservers,omitempty"`
+	Domain      string   `json:"domain,omitempty"`
+	Search      []string `json:"search,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}